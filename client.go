@@ -9,17 +9,24 @@ import (
 	"github.com/common-fate/analytics-go/acore"
 	"github.com/segmentio/ksuid"
 	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
 )
 
 const (
 	DevEndpoint     = "https://t-dev.commonfate.io"
 	DefaultEndpoint = "https://t.commonfate.io"
+
+	defaultInterval  = time.Millisecond * 50
+	defaultBatchSize = 3
 )
 
 type Client struct {
 	mu           *sync.Mutex
 	deploymentID *string
+	deployment   *Deployment
 	coreclient   acore.Client
+	logger       *zap.Logger
+	callback     *debugCallback
 
 	// A function called by the client to generate unique message identifiers.
 	// The client uses a UUID generator if none is provided.
@@ -33,6 +40,7 @@ func newClient(coreclient acore.Client) *Client {
 		mu:         &sync.Mutex{},
 		coreclient: coreclient,
 		uid:        func() string { return "anon_" + ksuid.New().String() },
+		logger:     zap.NewNop(),
 	}
 }
 
@@ -66,47 +74,59 @@ func endpointOrDefault(endpoint string) string {
 	return endpoint
 }
 
-type debugCallback struct{}
-
-func (debugCallback) Success(m acore.APIMessage) {
-	if os.Getenv("CF_ANALYTICS_DEBUG") == "true" {
-		zap.L().Named("cf-analytics").Info("event success", zap.Any("event", m))
-	}
-}
-
-func (debugCallback) Failure(m acore.APIMessage, err error) {
-	if os.Getenv("CF_ANALYTICS_DEBUG") == "true" {
-		zap.L().Named("cf-analytics").Error("event failure", zap.Any("event", m), zap.Error(err))
-	}
-}
-
 // New creates an analytics client.
 // Usage:
 //
 //	analytics.New(analytics.Development)
-func New(c Config) *Client {
+func New(c Config, opts ...Option) *Client {
+	for _, opt := range opts {
+		opt(&c)
+	}
+
+	logger := resolveLogger(c)
+
 	// create a no-op client if analytics are disabled.
 	if !c.Enabled {
-		return newClient(&acore.NoopClient{})
+		cl := newClient(&acore.NoopClient{})
+		cl.logger = logger
+		return cl
 	}
 
-	client, err := acore.NewWithConfig(acore.Config{
-		Endpoint:  c.Endpoint,
-		Callback:  debugCallback{},
-		Verbose:   c.Verbose,
-		Interval:  time.Millisecond * 50,
-		BatchSize: 3,
-	})
+	callback := &debugCallback{
+		logger:    logger,
+		endpoint:  endpointOrDefault(c.Endpoint),
+		batchSize: defaultBatchSize,
+	}
+
+	client, err := buildTransport(c, callback)
 	if err != nil {
-		zap.L().Named("cf-analytics").Error("error setting client", zap.Error(err))
-		return newClient(&acore.NoopClient{})
+		logger.Error("error setting client", zap.Error(err))
+		cl := newClient(&acore.NoopClient{})
+		cl.logger = logger
+		return cl
+	}
+
+	if c.SpoolDir != "" {
+		spool := NewSpoolTransport(client, c.SpoolDir)
+		spool.Logger = logger
+		// The default batched transport's Enqueue buffers and returns nil
+		// immediately; its real delivery failures only surface later
+		// through callback. Route those into the spool too, since
+		// spool.Enqueue's own synchronous check never sees them.
+		callback.onFailure = spool.spoolAsync
+		client = spool
 	}
 
-	if os.Getenv("CF_ANALYTICS_DEBUG") == "true" {
-		zap.L().Named("cf-analytics").Info("configured analytics client", zap.Any("config", c))
+	for _, mw := range c.Middlewares {
+		client = mw(client)
 	}
 
-	return newClient(client)
+	logger.Debug("configured analytics client", zap.Any("config", c))
+
+	cl := newClient(client)
+	cl.logger = logger
+	cl.callback = callback
+	return cl
 }
 
 // NewFromEnv sets up the analytics client based on the following
@@ -114,11 +134,13 @@ func New(c Config) *Client {
 //
 // - URL is CF_ANALYTICS_URL, or falls back to the default URL if not provided
 // - Disabled if CF_ANALYTICS_DISABLED is true
+// - LogLevel is CF_ANALYTICS_LOG_LEVEL ("debug", "info", "warn" or "error")
 func Env() Config {
 	return Config{
 		Endpoint: endpointOrDefault(os.Getenv("CF_ANALYTICS_URL")),
 		Enabled:  strings.ToLower(os.Getenv("CF_ANALYTICS_DISABLED")) != "true",
 		Verbose:  strings.ToLower(os.Getenv("CF_ANALYTICS_DEBUG")) == "true",
+		LogLevel: logLevelFromEnv(),
 	}
 }
 
@@ -127,20 +149,52 @@ type Config struct {
 	Endpoint string `json:"endpoint"`
 	Enabled  bool   `json:"enabled"`
 	Verbose  bool   `json:"verbose"`
+
+	// Transport overrides how events are delivered. If nil, events are sent
+	// according to Format, defaulting to batched JSON posted to Endpoint.
+	Transport Transport `json:"-"`
+	// Format selects the wire format used when Transport is nil. Defaults
+	// to FormatJSON.
+	Format Format `json:"format"`
+	// SpoolDir, if set, wraps the transport in a SpoolTransport that writes
+	// events to newline-delimited JSON files under this directory when the
+	// transport is unreachable, and drains them on reconnect.
+	SpoolDir string `json:"spoolDir"`
+	// Middlewares wrap the transport, in order, to add behaviour like
+	// sampling, rate-limiting or event-type filtering. See
+	// SamplingMiddleware, RateLimitMiddleware and FilterMiddleware.
+	Middlewares []Middleware `json:"-"`
+
+	// Logger receives structured logs for every event success/failure, plus
+	// lifecycle events like Close. Defaults to resolveLogger's fallback if
+	// nil: a logger at LogLevel, or a no-op logger if LogLevel is unset.
+	Logger *zap.Logger `json:"-"`
+	// LogLevel controls the verbosity of the default logger built when
+	// Logger is nil. It has no effect if Logger is set. nil (the default)
+	// keeps the client silent; set it (or CF_ANALYTICS_LOG_LEVEL) to an
+	// explicit level, including zapcore.InfoLevel, to opt in. A pointer is
+	// used so an explicit "info" is distinguishable from "unset" -
+	// zapcore.InfoLevel is zap's zero value, so a bare zapcore.Level field
+	// can't tell the two apart.
+	LogLevel *zapcore.Level `json:"logLevel"`
 }
 
 // Close the client.
 func (c *Client) Close() {
-	if os.Getenv("CF_ANALYTICS_DEBUG") == "true" {
-		zap.L().Named("cf-analytics").Info("closing analytics client", zap.String("url", c.coreclient.EndpointURL()))
-	}
+	c.logger.Info("closing analytics client", zap.String("endpoint", c.coreclient.EndpointURL()))
 
 	err := c.coreclient.Close()
 	if err != nil {
-		zap.L().Named("cf-analytics").Error("error closing client", zap.Error(err))
+		c.logger.Error("error closing client", zap.Error(err))
 	}
 }
 
+// Logger returns the logger configured for this client, so downstream
+// packages can emit related logs consistently.
+func (c *Client) Logger() *zap.Logger {
+	return c.logger
+}
+
 // SetDeploymentID sets the deployment ID.
 func (c *Client) SetDeploymentID(depID string) {
 	if depID == "" {
@@ -150,7 +204,12 @@ func (c *Client) SetDeploymentID(depID string) {
 	defer c.mu.Unlock()
 	c.deploymentID = &depID
 
-	if os.Getenv("CF_ANALYTICS_DEBUG") == "true" {
-		zap.L().Named("cf-analytics").Info("set deployment", zap.Any("deployment.id", depID))
+	if da, ok := c.coreclient.(DeploymentAware); ok {
+		da.SetDeploymentID(depID)
 	}
+	if c.callback != nil {
+		c.callback.SetDeploymentID(depID)
+	}
+
+	c.logger.Info("set deployment", zap.String("deployment.id", depID))
 }
\ No newline at end of file
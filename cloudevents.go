@@ -0,0 +1,222 @@
+package analytics
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/common-fate/analytics-go/acore"
+	"github.com/segmentio/ksuid"
+)
+
+const cloudEventsSpecVersion = "1.0"
+
+// defaultCloudEventsTimeout bounds how long a single Enqueue call can block
+// on the network, since CloudEventsTransport delivers synchronously on the
+// caller's goroutine.
+const defaultCloudEventsTimeout = 10 * time.Second
+
+// CloudEventsConfig configures a CloudEventsTransport.
+type CloudEventsConfig struct {
+	// Endpoint receives the CloudEvents HTTP requests.
+	Endpoint string
+	// Format selects binary or structured content mode. Defaults to
+	// FormatCloudEventsStructured.
+	Format Format
+	// HTTPClient is used to deliver events. Defaults to http.DefaultClient.
+	HTTPClient *http.Client
+	// Timeout bounds each Enqueue call's HTTP request. Defaults to
+	// defaultCloudEventsTimeout. Has no effect if HTTPClient is set and
+	// already has a non-zero Timeout.
+	Timeout time.Duration
+}
+
+// CloudEventsTransport delivers analytics events as CloudEvents v1.0
+// messages (https://github.com/cloudevents/spec), mapping each
+// acore.APIMessage to an event with type io.commonfate.analytics.<event>,
+// source urn:commonfate:deployment:<id>, id <ksuid>, time <RFC3339> and the
+// message body as the event data.
+//
+// Unlike the default batched HTTP transport, Enqueue delivers synchronously
+// on the caller's goroutine: it blocks for the duration of one HTTP
+// round-trip (bounded by CloudEventsConfig.Timeout), and a caller enqueueing
+// on a hot path will stall if the endpoint is slow or unreachable.
+type CloudEventsTransport struct {
+	endpoint   string
+	format     Format
+	httpClient *http.Client
+	timeout    time.Duration
+
+	mu           sync.RWMutex
+	deploymentID string
+}
+
+var _ Transport = (*CloudEventsTransport)(nil)
+var _ DeploymentAware = (*CloudEventsTransport)(nil)
+
+// NewCloudEventsTransport creates a CloudEventsTransport from c.
+func NewCloudEventsTransport(c CloudEventsConfig) *CloudEventsTransport {
+	format := c.Format
+	if format == "" {
+		format = FormatCloudEventsStructured
+	}
+	httpClient := c.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	timeout := c.Timeout
+	if c.HTTPClient != nil && c.HTTPClient.Timeout > 0 {
+		// The caller's own HTTPClient already bounds the request; don't
+		// also impose Timeout or defaultCloudEventsTimeout on top of it.
+		timeout = 0
+	} else if timeout <= 0 {
+		timeout = defaultCloudEventsTimeout
+	}
+
+	return &CloudEventsTransport{
+		endpoint:   endpointOrDefault(c.Endpoint),
+		format:     format,
+		httpClient: httpClient,
+		timeout:    timeout,
+	}
+}
+
+// SetDeploymentID updates the deployment ID used to build the CloudEvents
+// "source" attribute. It satisfies DeploymentAware.
+func (t *CloudEventsTransport) SetDeploymentID(id string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.deploymentID = id
+}
+
+// EndpointURL returns the endpoint events are delivered to.
+func (t *CloudEventsTransport) EndpointURL() string {
+	return t.endpoint
+}
+
+// Enqueue sends m to the configured endpoint as a single CloudEvent. It
+// blocks for the duration of the HTTP round-trip, bounded by t.timeout; see
+// the CloudEventsTransport doc comment.
+func (t *CloudEventsTransport) Enqueue(m acore.APIMessage) error {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("marshalling event: %w", err)
+	}
+
+	t.mu.RLock()
+	source := "urn:commonfate:deployment:" + t.deploymentID
+	t.mu.RUnlock()
+
+	event := cloudEvent{
+		SpecVersion: cloudEventsSpecVersion,
+		ID:          ksuid.New().String(),
+		Source:      source,
+		Type:        "io.commonfate.analytics." + eventName(data),
+		Time:        time.Now().UTC().Format(time.RFC3339),
+	}
+
+	req, err := t.newRequest(event, data)
+	if err != nil {
+		return err
+	}
+
+	ctx := req.Context()
+	if t.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, t.timeout)
+		defer cancel()
+	}
+
+	resp, err := t.httpClient.Do(req.WithContext(ctx))
+	if err != nil {
+		return fmt.Errorf("sending cloudevent: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("cloudevents endpoint returned status %d", resp.StatusCode)
+	}
+
+	return nil
+}
+
+// Close implements acore.Client. The CloudEvents transport has no
+// background resources to release.
+func (t *CloudEventsTransport) Close() error {
+	return nil
+}
+
+// cloudEvent holds the CloudEvents v1.0 context attributes used by both
+// content modes. Data is carried separately so it can be embedded (structured
+// mode) or sent as the raw request body (binary mode).
+type cloudEvent struct {
+	SpecVersion string `json:"specversion"`
+	ID          string `json:"id"`
+	Source      string `json:"source"`
+	Type        string `json:"type"`
+	Time        string `json:"time"`
+}
+
+// newRequest builds the HTTP request for event carrying data, using binary
+// or structured content mode depending on t.format.
+func (t *CloudEventsTransport) newRequest(event cloudEvent, data []byte) (*http.Request, error) {
+	if t.format == FormatCloudEventsBinary {
+		req, err := http.NewRequest(http.MethodPost, t.endpoint, bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("building cloudevent request: %w", err)
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("ce-specversion", event.SpecVersion)
+		req.Header.Set("ce-id", event.ID)
+		req.Header.Set("ce-source", event.Source)
+		req.Header.Set("ce-type", event.Type)
+		req.Header.Set("ce-time", event.Time)
+		return req, nil
+	}
+
+	body, err := json.Marshal(structuredCloudEvent{
+		cloudEvent: event,
+		Data:       json.RawMessage(data),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("marshalling structured cloudevent: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("building cloudevent request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/cloudevents+json")
+	return req, nil
+}
+
+// structuredCloudEvent is the structured content mode envelope: attributes
+// and data combined into a single JSON body.
+type structuredCloudEvent struct {
+	cloudEvent
+	Data json.RawMessage `json:"data"`
+}
+
+// eventName derives the CloudEvents "type" suffix from a JSON-encoded
+// acore.APIMessage by probing its event/type field, since acore.APIMessage
+// doesn't expose a single stable field name across message kinds.
+func eventName(raw []byte) string {
+	var probe struct {
+		Event string `json:"event"`
+		Type  string `json:"type"`
+	}
+	if err := json.Unmarshal(raw, &probe); err != nil {
+		return "unknown"
+	}
+	if probe.Event != "" {
+		return probe.Event
+	}
+	if probe.Type != "" {
+		return probe.Type
+	}
+	return "unknown"
+}
@@ -0,0 +1,180 @@
+package analytics
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// capturedRequest records what the test server observed for one CloudEvents
+// request, so assertions can run after the handler returns.
+type capturedRequest struct {
+	method      string
+	contentType string
+	header      http.Header
+	body        []byte
+}
+
+func serveAndCapture(t *testing.T, status int, delay time.Duration) (*httptest.Server, *capturedRequest) {
+	t.Helper()
+	captured := &capturedRequest{}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if delay > 0 {
+			time.Sleep(delay)
+		}
+		body, _ := io.ReadAll(r.Body)
+		captured.method = r.Method
+		captured.contentType = r.Header.Get("Content-Type")
+		captured.header = r.Header.Clone()
+		captured.body = body
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, captured
+}
+
+// TestCloudEventsTransportBinaryFormat confirms binary content mode sends
+// CloudEvents context attributes as ce-* headers and the raw message body
+// as the HTTP body.
+func TestCloudEventsTransportBinaryFormat(t *testing.T) {
+	srv, captured := serveAndCapture(t, http.StatusOK, 0)
+
+	ce := NewCloudEventsTransport(CloudEventsConfig{
+		Endpoint: srv.URL,
+		Format:   FormatCloudEventsBinary,
+	})
+	ce.SetDeploymentID("dep_123")
+
+	msg := fakeMessage{MessageID: "evt_1", Event: "test.event"}
+	if err := ce.Enqueue(msg); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	if captured.method != http.MethodPost {
+		t.Fatalf("method = %q, want POST", captured.method)
+	}
+	if captured.contentType != "application/json" {
+		t.Fatalf("Content-Type = %q, want application/json", captured.contentType)
+	}
+	if got := captured.header.Get("ce-specversion"); got != cloudEventsSpecVersion {
+		t.Fatalf("ce-specversion = %q, want %q", got, cloudEventsSpecVersion)
+	}
+	if got := captured.header.Get("ce-source"); got != "urn:commonfate:deployment:dep_123" {
+		t.Fatalf("ce-source = %q, want urn:commonfate:deployment:dep_123", got)
+	}
+	if got := captured.header.Get("ce-type"); got != "io.commonfate.analytics.test.event" {
+		t.Fatalf("ce-type = %q, want io.commonfate.analytics.test.event", got)
+	}
+	if captured.header.Get("ce-id") == "" {
+		t.Fatal("expected a non-empty ce-id header")
+	}
+	if string(captured.body) != `{"messageId":"evt_1","event":"test.event"}` {
+		t.Fatalf("body = %s, want the raw message JSON", captured.body)
+	}
+}
+
+// TestCloudEventsTransportStructuredFormat confirms structured content mode
+// (the default) sends a single JSON body combining the CloudEvents context
+// attributes and the message as "data".
+func TestCloudEventsTransportStructuredFormat(t *testing.T) {
+	srv, captured := serveAndCapture(t, http.StatusOK, 0)
+
+	ce := NewCloudEventsTransport(CloudEventsConfig{Endpoint: srv.URL})
+	ce.SetDeploymentID("dep_456")
+
+	msg := fakeMessage{MessageID: "evt_2", Event: "structured.event"}
+	if err := ce.Enqueue(msg); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	if captured.contentType != "application/cloudevents+json" {
+		t.Fatalf("Content-Type = %q, want application/cloudevents+json", captured.contentType)
+	}
+	if captured.header.Get("ce-id") != "" {
+		t.Fatal("structured mode should not set ce-* headers")
+	}
+
+	var got structuredCloudEvent
+	if err := json.Unmarshal(captured.body, &got); err != nil {
+		t.Fatalf("unmarshalling structured body: %v", err)
+	}
+	if got.Source != "urn:commonfate:deployment:dep_456" {
+		t.Fatalf("source = %q, want urn:commonfate:deployment:dep_456", got.Source)
+	}
+	if got.Type != "io.commonfate.analytics.structured.event" {
+		t.Fatalf("type = %q, want io.commonfate.analytics.structured.event", got.Type)
+	}
+	if string(got.Data) != `{"messageId":"evt_2","event":"structured.event"}` {
+		t.Fatalf("data = %s, want the raw message JSON", got.Data)
+	}
+}
+
+// TestCloudEventsTransportStatusError confirms a non-2xx response is
+// surfaced as an error rather than treated as success.
+func TestCloudEventsTransportStatusError(t *testing.T) {
+	srv, _ := serveAndCapture(t, http.StatusInternalServerError, 0)
+
+	ce := NewCloudEventsTransport(CloudEventsConfig{Endpoint: srv.URL})
+	if err := ce.Enqueue(fakeMessage{MessageID: "evt_1", Event: "test.event"}); err == nil {
+		t.Fatal("expected an error for a 500 response")
+	}
+}
+
+// TestCloudEventsTransportTimeoutPrecedence confirms the precedence
+// documented on CloudEventsConfig.Timeout: a caller-supplied HTTPClient with
+// its own non-zero Timeout is left alone, while Config.Timeout still
+// applies when HTTPClient has no timeout of its own.
+func TestCloudEventsTransportTimeoutPrecedence(t *testing.T) {
+	const serverDelay = 300 * time.Millisecond
+	const boundedTimeout = 100 * time.Millisecond
+
+	t.Run("caller HTTPClient.Timeout takes precedence", func(t *testing.T) {
+		srv, _ := serveAndCapture(t, http.StatusOK, serverDelay)
+
+		ce := NewCloudEventsTransport(CloudEventsConfig{
+			Endpoint:   srv.URL,
+			HTTPClient: &http.Client{Timeout: boundedTimeout},
+		})
+
+		start := time.Now()
+		err := ce.Enqueue(fakeMessage{MessageID: "evt_1", Event: "test.event"})
+		elapsed := time.Since(start)
+
+		if err == nil {
+			t.Fatal("expected the request to be cut off by HTTPClient.Timeout")
+		}
+		if elapsed >= serverDelay {
+			t.Fatalf("request took %v, expected it to be bounded by HTTPClient.Timeout (%v), not wait for the %v server delay", elapsed, boundedTimeout, serverDelay)
+		}
+	})
+
+	t.Run("Config.Timeout applies when HTTPClient has none", func(t *testing.T) {
+		srv, _ := serveAndCapture(t, http.StatusOK, serverDelay)
+
+		ce := NewCloudEventsTransport(CloudEventsConfig{
+			Endpoint: srv.URL,
+			Timeout:  boundedTimeout,
+		})
+
+		start := time.Now()
+		err := ce.Enqueue(fakeMessage{MessageID: "evt_1", Event: "test.event"})
+		elapsed := time.Since(start)
+
+		if err == nil {
+			t.Fatal("expected the request to be cut off by Config.Timeout")
+		}
+		if elapsed >= serverDelay {
+			t.Fatalf("request took %v, expected it to be bounded by Config.Timeout (%v), not wait for the %v server delay", elapsed, boundedTimeout, serverDelay)
+		}
+	})
+
+	t.Run("default timeout applies when neither is set", func(t *testing.T) {
+		ce := NewCloudEventsTransport(CloudEventsConfig{Endpoint: "http://example.invalid"})
+		if ce.timeout != defaultCloudEventsTimeout {
+			t.Fatalf("timeout = %v, want the default %v", ce.timeout, defaultCloudEventsTimeout)
+		}
+	})
+}
@@ -1,8 +1,6 @@
 package analytics
 
 import (
-	"os"
-
 	"github.com/common-fate/analytics-go/acore"
 	"go.uber.org/zap"
 )
@@ -14,6 +12,18 @@ type Deployment struct {
 	ID      string `json:"id"`
 	Version string `json:"version"`
 	Stage   string `json:"stage"` // dev, prod, uat, etc.
+
+	// Identity derives ID automatically when ID is empty, by hashing
+	// Identity.Fingerprint() with SHA-256 and prefixing it with "dep_". Set
+	// it to one of KubernetesNamespaceUID, MachineID or EnvFingerprint (or a
+	// custom implementation) to keep the deployment identifier stable
+	// across restarts without assigning one by hand.
+	Identity DeploymentIdentity `json:"-"`
+
+	// idSource records which DeploymentIdentity produced ID, surfaced in
+	// Traits() so downstream consumers can tell derived IDs apart from
+	// ones assigned by the caller.
+	idSource string
 }
 
 // Traits returns the traits to use for the group identifier
@@ -27,17 +37,53 @@ func (d Deployment) Traits() acore.Traits {
 	if d.Stage != "" {
 		t = t.Set("stage", d.Stage)
 	}
+	if d.idSource != "" {
+		t = t.Set("idSource", d.idSource)
+	}
 
 	return t
 }
 
-// SetDeployment sets deployment information.
+// resolveID fills in d.ID and d.idSource from d.Identity's fingerprint, when
+// d.ID is empty and d.Identity is set.
+func (d *Deployment) resolveID(logger *zap.Logger) {
+	if d.ID != "" || d.Identity == nil {
+		return
+	}
+
+	fp, err := d.Identity.Fingerprint()
+	if err != nil {
+		logger.Warn("deriving deployment id", zap.Error(err))
+		return
+	}
+
+	d.ID = deriveDeploymentID(fp)
+	d.idSource = identitySource(d.Identity)
+}
+
+// SetDeployment sets deployment information. If dep.ID is empty and
+// dep.Identity is set, the ID is derived from the identity's fingerprint
+// first. Fingerprinting can make a network call (KubernetesNamespaceUID),
+// so it runs before c.mu is taken to avoid blocking other SetDeployment or
+// SetDeploymentID callers behind it.
 func (c *Client) SetDeployment(dep *Deployment) {
+	if dep != nil {
+		dep.resolveID(c.logger)
+	}
+
 	c.mu.Lock()
 	defer c.mu.Unlock()
+
 	c.deployment = dep
 
-	if os.Getenv("CF_ANALYTICS_DEBUG") == "true" {
-		zap.L().Named("cf-analytics").Info("set deployment", zap.Any("deployment", dep))
+	if dep != nil {
+		if da, ok := c.coreclient.(DeploymentAware); ok {
+			da.SetDeploymentID(dep.ID)
+		}
+		if c.callback != nil {
+			c.callback.SetDeploymentID(dep.ID)
+		}
 	}
+
+	c.logger.Info("set deployment", zap.Any("deployment", dep))
 }
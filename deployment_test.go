@@ -0,0 +1,61 @@
+package analytics
+
+import (
+	"testing"
+)
+
+// TestClientSetDeploymentDerivesIDFromIdentity confirms SetDeployment fills
+// in Deployment.ID from Identity.Fingerprint when ID is empty, and surfaces
+// the identity source in Traits()["idSource"].
+func TestClientSetDeploymentDerivesIDFromIdentity(t *testing.T) {
+	t.Setenv("CF_TEST_DEPLOY_FP", "stable-value")
+
+	client := newClient(&fakeTransport{})
+
+	dep := &Deployment{Identity: EnvFingerprint{Vars: []string{"CF_TEST_DEPLOY_FP"}}}
+	client.SetDeployment(dep)
+
+	wantID := deriveDeploymentID("CF_TEST_DEPLOY_FP=stable-value\n")
+	if dep.ID != wantID {
+		t.Fatalf("ID = %q, want %q", dep.ID, wantID)
+	}
+
+	traits := dep.Traits()
+	if got := traits["idSource"]; got != envFingerprintSource {
+		t.Fatalf("Traits()[\"idSource\"] = %v, want %q", got, envFingerprintSource)
+	}
+	if got := traits["id"]; got != dep.ID {
+		t.Fatalf("Traits()[\"id\"] = %v, want %q", got, dep.ID)
+	}
+}
+
+// TestClientSetDeploymentLeavesExplicitIDUntouched confirms SetDeployment
+// never overwrites a caller-supplied ID, even when Identity is also set,
+// and that idSource is left unset in that case.
+func TestClientSetDeploymentLeavesExplicitIDUntouched(t *testing.T) {
+	client := newClient(&fakeTransport{})
+
+	dep := &Deployment{ID: "explicit-id", Identity: EnvFingerprint{Vars: []string{"PATH"}}}
+	client.SetDeployment(dep)
+
+	if dep.ID != "explicit-id" {
+		t.Fatalf("ID = %q, want the explicitly set ID left untouched", dep.ID)
+	}
+	if got := dep.Traits()["idSource"]; got != nil {
+		t.Fatalf("Traits()[\"idSource\"] = %v, want unset when ID was supplied explicitly", got)
+	}
+}
+
+// TestClientSetDeploymentNoIdentityLeavesIDEmpty confirms a Deployment
+// without an Identity is passed through unchanged, for callers that assign
+// IDs by hand.
+func TestClientSetDeploymentNoIdentityLeavesIDEmpty(t *testing.T) {
+	client := newClient(&fakeTransport{})
+
+	dep := &Deployment{}
+	client.SetDeployment(dep)
+
+	if dep.ID != "" {
+		t.Fatalf("ID = %q, want empty when no Identity is configured", dep.ID)
+	}
+}
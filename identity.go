@@ -0,0 +1,249 @@
+package analytics
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"time"
+)
+
+// defaultKubernetesNamespaceUIDTimeout bounds how long Fingerprint can block
+// calling the Kubernetes API, since it runs synchronously from
+// Client.SetDeployment.
+const defaultKubernetesNamespaceUIDTimeout = 5 * time.Second
+
+// DeploymentIdentity derives a stable anonymous fingerprint for the current
+// environment, used by Client.SetDeployment to fill in Deployment.ID when
+// it's left empty. Implementations should return the same value across
+// restarts of the same machine or cluster.
+type DeploymentIdentity interface {
+	// Fingerprint returns a raw, source-specific identifier. Callers hash it
+	// before using it as a Deployment.ID.
+	Fingerprint() (string, error)
+}
+
+const (
+	kubernetesNamespaceUIDSource = "kubernetesNamespaceUID"
+	machineIDSource              = "machineID"
+	envFingerprintSource         = "envFingerprint"
+)
+
+// deriveDeploymentID hashes fingerprint with SHA-256 and prefixes it with
+// "dep_", so the raw fingerprint is never sent as-is.
+func deriveDeploymentID(fingerprint string) string {
+	sum := sha256.Sum256([]byte(fingerprint))
+	return "dep_" + hex.EncodeToString(sum[:])
+}
+
+// identitySource names the DeploymentIdentity implementation that produced
+// a fingerprint, for Deployment.Traits' "idSource" field.
+func identitySource(identity DeploymentIdentity) string {
+	switch identity.(type) {
+	case KubernetesNamespaceUID:
+		return kubernetesNamespaceUIDSource
+	case MachineID:
+		return machineIDSource
+	case EnvFingerprint:
+		return envFingerprintSource
+	default:
+		return "custom"
+	}
+}
+
+// KubernetesNamespaceUID fingerprints a cluster by the UID Kubernetes
+// assigned to the kube-system namespace, which is stable for the lifetime
+// of the cluster. It only works from inside a pod with in-cluster
+// credentials; use MachineID or EnvFingerprint otherwise.
+type KubernetesNamespaceUID struct {
+	// Namespace overrides the namespace whose UID is used. Defaults to
+	// kube-system.
+	Namespace string
+	// HTTPClient is used to call the Kubernetes API. Defaults to a client
+	// trusting the in-cluster CA certificate.
+	HTTPClient *http.Client
+	// Timeout bounds the Kubernetes API call. Defaults to
+	// defaultKubernetesNamespaceUIDTimeout. Has no effect if HTTPClient is
+	// set and already has a non-zero Timeout.
+	Timeout time.Duration
+}
+
+// Fingerprint implements DeploymentIdentity.
+func (k KubernetesNamespaceUID) Fingerprint() (string, error) {
+	namespace := k.Namespace
+	if namespace == "" {
+		namespace = "kube-system"
+	}
+
+	host := os.Getenv("KUBERNETES_SERVICE_HOST")
+	port := os.Getenv("KUBERNETES_SERVICE_PORT")
+	if host == "" || port == "" {
+		return "", fmt.Errorf("not running in a kubernetes cluster: KUBERNETES_SERVICE_HOST or KUBERNETES_SERVICE_PORT is unset")
+	}
+
+	token, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/token")
+	if err != nil {
+		return "", fmt.Errorf("reading in-cluster service account token: %w", err)
+	}
+
+	client := k.HTTPClient
+	if client == nil {
+		pool, err := inClusterCAPool()
+		if err != nil {
+			return "", err
+		}
+		client = &http.Client{
+			Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: pool},
+			},
+		}
+	}
+
+	timeout := k.Timeout
+	if k.HTTPClient != nil && k.HTTPClient.Timeout > 0 {
+		timeout = 0
+	} else if timeout <= 0 {
+		timeout = defaultKubernetesNamespaceUIDTimeout
+	}
+
+	url := fmt.Sprintf("https://%s/api/v1/namespaces/%s", net.JoinHostPort(host, port), namespace)
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return "", fmt.Errorf("building kubernetes api request: %w", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+string(token))
+
+	if timeout > 0 {
+		ctx, cancel := context.WithTimeout(req.Context(), timeout)
+		defer cancel()
+		req = req.WithContext(ctx)
+	}
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("calling kubernetes api: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("kubernetes api returned status %d", resp.StatusCode)
+	}
+
+	var ns struct {
+		Metadata struct {
+			UID string `json:"uid"`
+		} `json:"metadata"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&ns); err != nil {
+		return "", fmt.Errorf("decoding namespace response: %w", err)
+	}
+	if ns.Metadata.UID == "" {
+		return "", fmt.Errorf("namespace %s has no uid", namespace)
+	}
+
+	return ns.Metadata.UID, nil
+}
+
+// inClusterCAPool loads the CA certificate Kubernetes mounts into every pod,
+// so KubernetesNamespaceUID can verify the API server without a full
+// in-cluster client.
+func inClusterCAPool() (*x509.CertPool, error) {
+	ca, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/ca.crt")
+	if err != nil {
+		return nil, fmt.Errorf("reading in-cluster ca certificate: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("parsing in-cluster ca certificate")
+	}
+	return pool, nil
+}
+
+// MachineID fingerprints a host using its D-Bus machine ID, falling back to
+// the MAC address of its first non-loopback network interface when neither
+// machine-id file is present.
+type MachineID struct{}
+
+// Fingerprint implements DeploymentIdentity.
+func (MachineID) Fingerprint() (string, error) {
+	for _, path := range []string{"/etc/machine-id", "/var/lib/dbus/machine-id"} {
+		raw, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if id := strings.TrimSpace(string(raw)); id != "" {
+			return id, nil
+		}
+	}
+
+	mac, err := firstNonLoopbackMAC()
+	if err != nil {
+		return "", fmt.Errorf("no machine-id file and no usable network interface: %w", err)
+	}
+	return mac, nil
+}
+
+// firstNonLoopbackMAC returns the hardware address of the first interface
+// that isn't loopback and has a MAC address.
+func firstNonLoopbackMAC() (string, error) {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return "", fmt.Errorf("listing network interfaces: %w", err)
+	}
+
+	for _, iface := range ifaces {
+		if iface.Flags&net.FlagLoopback != 0 || len(iface.HardwareAddr) == 0 {
+			continue
+		}
+		return iface.HardwareAddr.String(), nil
+	}
+
+	return "", fmt.Errorf("no non-loopback network interface found")
+}
+
+// EnvFingerprint fingerprints the environment from the values of a
+// configurable set of environment variables, read in sorted key order so
+// the result doesn't depend on how Vars is listed. Useful on platforms
+// where neither a cluster nor a stable machine ID is available.
+type EnvFingerprint struct {
+	// Vars lists the environment variables to include in the fingerprint.
+	// Unset variables are skipped.
+	Vars []string
+}
+
+// Fingerprint implements DeploymentIdentity.
+func (e EnvFingerprint) Fingerprint() (string, error) {
+	if len(e.Vars) == 0 {
+		return "", fmt.Errorf("envfingerprint: no environment variables configured")
+	}
+
+	vars := append([]string(nil), e.Vars...)
+	sort.Strings(vars)
+
+	var b strings.Builder
+	found := false
+	for _, name := range vars {
+		value := os.Getenv(name)
+		if value == "" {
+			continue
+		}
+		found = true
+		b.WriteString(name)
+		b.WriteByte('=')
+		b.WriteString(value)
+		b.WriteByte('\n')
+	}
+	if !found {
+		return "", fmt.Errorf("envfingerprint: none of the configured environment variables are set")
+	}
+
+	return b.String(), nil
+}
@@ -0,0 +1,79 @@
+package analytics
+
+import (
+	"testing"
+)
+
+// TestEnvFingerprintSortedKeyOrder confirms Fingerprint reads variables in
+// sorted key order, so the result doesn't depend on how Vars is listed.
+func TestEnvFingerprintSortedKeyOrder(t *testing.T) {
+	t.Setenv("CF_TEST_FP_A", "one")
+	t.Setenv("CF_TEST_FP_B", "two")
+
+	forward := EnvFingerprint{Vars: []string{"CF_TEST_FP_A", "CF_TEST_FP_B"}}
+	reverse := EnvFingerprint{Vars: []string{"CF_TEST_FP_B", "CF_TEST_FP_A"}}
+
+	got, err := forward.Fingerprint()
+	if err != nil {
+		t.Fatalf("Fingerprint: %v", err)
+	}
+	want, err := reverse.Fingerprint()
+	if err != nil {
+		t.Fatalf("Fingerprint: %v", err)
+	}
+
+	if got != want {
+		t.Fatalf("Fingerprint order dependent: forward=%q reverse=%q", got, want)
+	}
+}
+
+// TestEnvFingerprintSkipsUnsetVars confirms unset variables are omitted
+// rather than contributing an empty value to the fingerprint.
+func TestEnvFingerprintSkipsUnsetVars(t *testing.T) {
+	t.Setenv("CF_TEST_FP_SET", "value")
+
+	withUnset := EnvFingerprint{Vars: []string{"CF_TEST_FP_SET", "CF_TEST_FP_NOT_SET"}}
+	withoutUnset := EnvFingerprint{Vars: []string{"CF_TEST_FP_SET"}}
+
+	got, err := withUnset.Fingerprint()
+	if err != nil {
+		t.Fatalf("Fingerprint: %v", err)
+	}
+	want, err := withoutUnset.Fingerprint()
+	if err != nil {
+		t.Fatalf("Fingerprint: %v", err)
+	}
+
+	if got != want {
+		t.Fatalf("unset variable changed the fingerprint: got=%q want=%q", got, want)
+	}
+}
+
+// TestEnvFingerprintNoneSet confirms Fingerprint fails rather than
+// returning an empty, unstable fingerprint when none of Vars is set.
+func TestEnvFingerprintNoneSet(t *testing.T) {
+	f := EnvFingerprint{Vars: []string{"CF_TEST_FP_DEFINITELY_UNSET"}}
+
+	if _, err := f.Fingerprint(); err == nil {
+		t.Fatal("expected an error when no configured variable is set")
+	}
+}
+
+// TestDeriveDeploymentIDStablePrefix confirms deriveDeploymentID always
+// hashes the raw fingerprint and prefixes it with "dep_", and that distinct
+// fingerprints never collide.
+func TestDeriveDeploymentIDStablePrefix(t *testing.T) {
+	a := deriveDeploymentID("fingerprint-a")
+	b := deriveDeploymentID("fingerprint-b")
+
+	if a == b {
+		t.Fatalf("distinct fingerprints produced the same id: %q", a)
+	}
+	if deriveDeploymentID("fingerprint-a") != a {
+		t.Fatal("deriveDeploymentID is not deterministic for the same input")
+	}
+	const prefix = "dep_"
+	if len(a) <= len(prefix) || a[:len(prefix)] != prefix {
+		t.Fatalf("id %q missing %q prefix", a, prefix)
+	}
+}
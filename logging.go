@@ -0,0 +1,235 @@
+package analytics
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/common-fate/analytics-go/acore"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// resolveLogger returns the logger a Client should use. It returns
+// c.Logger if set. Otherwise it builds a default logger at *c.LogLevel,
+// unless c.LogLevel is nil (unset), in which case it returns a no-op
+// logger so the client stays silent by default.
+func resolveLogger(c Config) *zap.Logger {
+	if c.Logger != nil {
+		return c.Logger
+	}
+
+	if c.LogLevel == nil {
+		return zap.NewNop()
+	}
+
+	cfg := zap.NewProductionConfig()
+	cfg.Level = zap.NewAtomicLevelAt(*c.LogLevel)
+	logger, err := cfg.Build()
+	if err != nil {
+		return zap.NewNop()
+	}
+
+	return logger.Named("cf-analytics")
+}
+
+// logLevelFromEnv parses CF_ANALYTICS_LOG_LEVEL ("debug", "info", "warn" or
+// "error"), returning nil (unset) if it's empty or unrecognised so callers
+// can tell "not configured" apart from an explicit "info".
+func logLevelFromEnv() *zapcore.Level {
+	var level zapcore.Level
+
+	switch strings.ToLower(os.Getenv("CF_ANALYTICS_LOG_LEVEL")) {
+	case "debug":
+		level = zapcore.DebugLevel
+	case "info":
+		level = zapcore.InfoLevel
+	case "warn":
+		level = zapcore.WarnLevel
+	case "error":
+		level = zapcore.ErrorLevel
+	default:
+		return nil
+	}
+
+	return &level
+}
+
+// debugCallback is the acore.Callback passed to the default HTTP transport,
+// and the shared logging sink every other transport logs through too (see
+// loggingClient). It logs structured fields for every delivered or failed
+// event.
+type debugCallback struct {
+	logger    *zap.Logger
+	endpoint  string
+	batchSize int
+
+	mu           sync.RWMutex
+	deploymentID string
+	// sentAt records when each in-flight message was enqueued, keyed by
+	// event.id, so latency_ms reflects actual delivery time rather than the
+	// message's own creation timestamp (which can lag well behind the real
+	// send once events are batched). Populated by recordEnqueue and
+	// consumed once by takeLatency.
+	sentAt map[string]time.Time
+	// onFailure, if set, is notified whenever a message fails delivery
+	// through this acore.Callback. It only fires for transports that report
+	// results asynchronously this way (the default batched HTTP transport);
+	// synchronous transports report failure through Enqueue's return value
+	// instead. SpoolTransport hooks in here so it can catch delivery
+	// failures that Enqueue's return value never surfaces for that
+	// transport.
+	onFailure func(m acore.APIMessage, err error)
+}
+
+var _ DeploymentAware = (*debugCallback)(nil)
+
+// SetDeploymentID records the deployment ID so it can be attached to
+// subsequent log lines. It satisfies DeploymentAware.
+func (d *debugCallback) SetDeploymentID(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.deploymentID = id
+}
+
+func (d *debugCallback) currentDeploymentID() string {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	return d.deploymentID
+}
+
+// recordEnqueue notes the time m was handed to a transport, so a later
+// Success/Failure call for the same message can report real delivery
+// latency. It's a no-op for messages acore doesn't assign an ID.
+func (d *debugCallback) recordEnqueue(m acore.APIMessage) {
+	id, _, _ := probeMessage(m)
+	if id == "" {
+		return
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if d.sentAt == nil {
+		d.sentAt = map[string]time.Time{}
+	}
+	d.sentAt[id] = time.Now()
+}
+
+// takeLatency returns the time elapsed since recordEnqueue was called for
+// m's ID, and forgets it. It returns 0 if no matching enqueue was recorded.
+func (d *debugCallback) takeLatency(m acore.APIMessage) time.Duration {
+	id, _, _ := probeMessage(m)
+	if id == "" {
+		return 0
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	start, ok := d.sentAt[id]
+	if !ok {
+		return 0
+	}
+	delete(d.sentAt, id)
+	return time.Since(start)
+}
+
+// Success implements acore.Callback.
+func (d *debugCallback) Success(m acore.APIMessage) {
+	d.log(m, nil, d.takeLatency(m))
+}
+
+// Failure implements acore.Callback.
+func (d *debugCallback) Failure(m acore.APIMessage, err error) {
+	d.log(m, err, d.takeLatency(m))
+	if d.onFailure != nil {
+		d.onFailure(m, err)
+	}
+}
+
+func (d *debugCallback) log(m acore.APIMessage, err error, latency time.Duration) {
+	id, eventType, _ := probeMessage(m)
+
+	fields := []zap.Field{
+		zap.String("event.id", id),
+		zap.String("event.type", eventType),
+		zap.String("deployment.id", d.currentDeploymentID()),
+		zap.String("endpoint", d.endpoint),
+		zap.Int("batch.size", d.batchSize),
+	}
+	if latency > 0 {
+		fields = append(fields, zap.Int64("latency_ms", latency.Milliseconds()))
+	}
+
+	if err != nil {
+		d.logger.Error("event failure", append(fields, zap.Error(err))...)
+		return
+	}
+	d.logger.Info("event success", fields...)
+}
+
+// probeMessage extracts the fields debugCallback logs out of m. acore
+// doesn't expose a single stable field name across message kinds, so this
+// probes the JSON encoding by tag rather than depending on m's Go type.
+func probeMessage(m acore.APIMessage) (id, eventType string, sentAt time.Time) {
+	raw, err := json.Marshal(m)
+	if err != nil {
+		return "", "", time.Time{}
+	}
+
+	var probe struct {
+		MessageID string    `json:"messageId"`
+		Event     string    `json:"event"`
+		Type      string    `json:"type"`
+		Timestamp time.Time `json:"timestamp"`
+	}
+	if err := json.Unmarshal(raw, &probe); err != nil {
+		return "", "", time.Time{}
+	}
+
+	eventType = probe.Event
+	if eventType == "" {
+		eventType = probe.Type
+	}
+
+	return probe.MessageID, eventType, probe.Timestamp
+}
+
+// loggingClient wraps a synchronous Transport (CloudEvents, or a
+// caller-supplied Config.Transport) so it reports through the same
+// debugCallback as the default batched HTTP transport. Those transports
+// resolve their Enqueue call's outcome immediately, instead of reporting it
+// later through an acore.Callback, so their latency is simply the time
+// Enqueue took.
+type loggingClient struct {
+	forwardingClient
+	callback *debugCallback
+}
+
+var _ Transport = loggingClient{}
+
+// Enqueue times the call to the wrapped transport and logs the outcome
+// through callback before returning it unchanged.
+func (l loggingClient) Enqueue(m acore.APIMessage) error {
+	start := time.Now()
+	err := l.next.Enqueue(m)
+	l.callback.log(m, err, time.Since(start))
+	return err
+}
+
+// callbackInstrumentedClient wraps the default batched HTTP transport so
+// callback can time each message from the moment it's actually enqueued.
+// That transport buffers and reports results later through the
+// acore.Callback it was given, which has no way on its own to know when
+// the send started.
+type callbackInstrumentedClient struct {
+	acore.Client
+	callback *debugCallback
+}
+
+// Enqueue records the enqueue time before forwarding to the wrapped client.
+func (c callbackInstrumentedClient) Enqueue(m acore.APIMessage) error {
+	c.callback.recordEnqueue(m)
+	return c.Client.Enqueue(m)
+}
@@ -0,0 +1,126 @@
+package analytics
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/common-fate/analytics-go/acore"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+var errEndpointUnreachable = errors.New("endpoint unreachable")
+
+// TestResolveLoggerPrecedence confirms Config.Logger always wins, and that
+// a nil LogLevel falls back to a no-op logger rather than a default level.
+func TestResolveLoggerPrecedence(t *testing.T) {
+	explicit := zap.NewNop()
+	if got := resolveLogger(Config{Logger: explicit}); got != explicit {
+		t.Fatal("Config.Logger was not returned as-is")
+	}
+
+	if got := resolveLogger(Config{}); got.Core().Enabled(zapcore.ErrorLevel) {
+		t.Fatal("expected a no-op logger when LogLevel and Logger are both unset")
+	}
+
+	level := zapcore.InfoLevel
+	got := resolveLogger(Config{LogLevel: &level})
+	if !got.Core().Enabled(zapcore.InfoLevel) {
+		t.Fatal("expected the built logger to be enabled at the configured level")
+	}
+}
+
+// TestLogLevelFromEnv confirms each recognised level parses, and that an
+// empty or unrecognised value returns nil (distinguishable from an
+// explicit "info").
+func TestLogLevelFromEnv(t *testing.T) {
+	cases := map[string]*zapcore.Level{
+		"debug": levelPtr(zapcore.DebugLevel),
+		"info":  levelPtr(zapcore.InfoLevel),
+		"warn":  levelPtr(zapcore.WarnLevel),
+		"error": levelPtr(zapcore.ErrorLevel),
+		"bogus": nil,
+		"":      nil,
+	}
+
+	for value, want := range cases {
+		t.Setenv("CF_ANALYTICS_LOG_LEVEL", value)
+		got := logLevelFromEnv()
+		if (got == nil) != (want == nil) {
+			t.Fatalf("value %q: got %v, want %v", value, got, want)
+		}
+		if got != nil && *got != *want {
+			t.Fatalf("value %q: got %v, want %v", value, *got, *want)
+		}
+	}
+}
+
+func levelPtr(l zapcore.Level) *zapcore.Level { return &l }
+
+// TestDebugCallbackLatency confirms Success reports a non-zero latency for
+// a message recorded via recordEnqueue, and zero when no enqueue was
+// recorded for that message's ID.
+func TestDebugCallbackLatency(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+	cb := &debugCallback{logger: zap.New(core), endpoint: "fake://endpoint", batchSize: 3}
+
+	msg := fakeMessage{MessageID: "evt_1", Event: "test.event"}
+	cb.recordEnqueue(msg)
+	time.Sleep(time.Millisecond)
+	cb.Success(msg)
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("expected 1 log entry, got %d", len(entries))
+	}
+	fields := entries[0].ContextMap()
+	latency, ok := fields["latency_ms"]
+	if !ok {
+		t.Fatal("expected latency_ms field to be present for a recorded enqueue")
+	}
+	if v, ok := latency.(int64); !ok || v <= 0 {
+		t.Fatalf("expected a positive latency_ms, got %v", latency)
+	}
+
+	logs.TakeAll()
+	cb.Success(fakeMessage{MessageID: "evt_unrecorded", Event: "test.event"})
+	entries = logs.All()
+	if _, ok := entries[0].ContextMap()["latency_ms"]; ok {
+		t.Fatal("expected no latency_ms field for a message with no recorded enqueue")
+	}
+}
+
+// TestDebugCallbackFailureNotifiesOnFailure confirms Failure both logs and
+// invokes onFailure, since SpoolTransport depends on the latter to catch
+// delivery failures the default batched transport only reports this way.
+func TestDebugCallbackFailureNotifiesOnFailure(t *testing.T) {
+	core, logs := observer.New(zapcore.DebugLevel)
+
+	var notified bool
+	var notifiedErr error
+	cb := &debugCallback{
+		logger:   zap.New(core),
+		endpoint: "fake://endpoint",
+		onFailure: func(m acore.APIMessage, err error) {
+			notified = true
+			notifiedErr = err
+		},
+	}
+
+	wantErr := errEndpointUnreachable
+	cb.Failure(fakeMessage{MessageID: "evt_1", Event: "test.event"}, wantErr)
+
+	if !notified {
+		t.Fatal("expected onFailure to be invoked")
+	}
+	if notifiedErr != wantErr {
+		t.Fatalf("onFailure got err %v, want %v", notifiedErr, wantErr)
+	}
+
+	entries := logs.All()
+	if len(entries) != 1 || entries[0].Level != zapcore.ErrorLevel {
+		t.Fatalf("expected a single error-level log entry, got %+v", entries)
+	}
+}
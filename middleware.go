@@ -0,0 +1,222 @@
+package analytics
+
+import (
+	"hash/fnv"
+	"sync"
+	"time"
+
+	"github.com/common-fate/analytics-go/acore"
+)
+
+// Middleware wraps an acore.Client to add cross-cutting behaviour, such as
+// sampling, rate-limiting or filtering, without changing how events are
+// produced at call sites. Middlewares are applied in Config.Middlewares
+// order, with later entries wrapping earlier ones.
+type Middleware func(next acore.Client) acore.Client
+
+// forwardingClient implements the acore.Client and DeploymentAware
+// passthroughs shared by every middleware in this file, so each one only
+// has to implement Enqueue.
+type forwardingClient struct {
+	next acore.Client
+}
+
+func (f forwardingClient) Close() error        { return f.next.Close() }
+func (f forwardingClient) EndpointURL() string { return f.next.EndpointURL() }
+
+func (f forwardingClient) SetDeploymentID(id string) {
+	if da, ok := f.next.(DeploymentAware); ok {
+		da.SetDeploymentID(id)
+	}
+}
+
+// SamplingMiddleware drops messages based on a deterministic hash of the
+// message ID modulo 10000, so retries of the same event are sampled
+// consistently. rate is the default sample rate in [0, 1]; perType
+// overrides it for specific event types.
+func SamplingMiddleware(rate float64, perType map[string]float64) Middleware {
+	return func(next acore.Client) acore.Client {
+		return &samplingClient{
+			forwardingClient: forwardingClient{next: next},
+			rate:             rate,
+			perType:          perType,
+		}
+	}
+}
+
+type samplingClient struct {
+	forwardingClient
+	rate    float64
+	perType map[string]float64
+}
+
+var _ Transport = (*samplingClient)(nil)
+
+func (s *samplingClient) Enqueue(m acore.APIMessage) error {
+	id, eventType, _ := probeMessage(m)
+
+	rate := s.rate
+	if r, ok := s.perType[eventType]; ok {
+		rate = r
+	}
+
+	if !sampledIn(id, rate) {
+		return nil
+	}
+
+	return s.next.Enqueue(m)
+}
+
+// sampledIn hashes id into [0, 10000) and compares the result against rate,
+// so the same message ID always samples the same way across retries.
+func sampledIn(id string, rate float64) bool {
+	if rate >= 1 {
+		return true
+	}
+	if rate <= 0 {
+		return false
+	}
+
+	h := fnv.New32a()
+	_, _ = h.Write([]byte(id))
+	bucket := h.Sum32() % 10000
+
+	return float64(bucket) < rate*10000
+}
+
+// DropOrBlock selects what a RateLimitMiddleware does once its token bucket
+// is empty.
+type DropOrBlock int
+
+const (
+	// Drop discards events once the rate limit is exceeded.
+	Drop DropOrBlock = iota
+	// Block waits for a token to become available before enqueuing.
+	Block
+)
+
+// RateLimitMiddleware throttles outgoing events to eventsPerSec using a
+// token bucket with room for burst events above that steady rate. onLimit
+// controls what happens once the bucket is empty. It's taken as a
+// constructor argument rather than a settable OnLimit field, matching
+// SamplingMiddleware and FilterMiddleware: every built-in
+// middleware here is configured once at construction time, not mutated
+// afterwards.
+func RateLimitMiddleware(eventsPerSec int, burst int, onLimit DropOrBlock) Middleware {
+	return func(next acore.Client) acore.Client {
+		return &rateLimitClient{
+			forwardingClient: forwardingClient{next: next},
+			bucket:           newTokenBucket(float64(eventsPerSec), float64(burst)),
+			onLimit:          onLimit,
+		}
+	}
+}
+
+type rateLimitClient struct {
+	forwardingClient
+	bucket  *tokenBucket
+	onLimit DropOrBlock
+}
+
+var _ Transport = (*rateLimitClient)(nil)
+
+func (r *rateLimitClient) Enqueue(m acore.APIMessage) error {
+	if r.bucket.take() {
+		return r.next.Enqueue(m)
+	}
+
+	if r.onLimit == Block {
+		r.bucket.wait()
+		return r.next.Enqueue(m)
+	}
+
+	return nil
+}
+
+// tokenBucket is a minimal token bucket rate limiter: it refills at
+// refillRate tokens per second up to max, and take/wait remove one token.
+type tokenBucket struct {
+	mu         sync.Mutex
+	tokens     float64
+	max        float64
+	refillRate float64
+	last       time.Time
+}
+
+func newTokenBucket(refillRate, max float64) *tokenBucket {
+	return &tokenBucket{
+		tokens:     max,
+		max:        max,
+		refillRate: refillRate,
+		last:       time.Now(),
+	}
+}
+
+// take removes a token if one is available, reporting whether it succeeded.
+func (b *tokenBucket) take() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	now := time.Now()
+	b.tokens += now.Sub(b.last).Seconds() * b.refillRate
+	if b.tokens > b.max {
+		b.tokens = b.max
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
+
+// wait blocks until a token is available.
+func (b *tokenBucket) wait() {
+	for !b.take() {
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+// FilterMiddleware drops messages by event type. If allow is non-empty,
+// only those event types pass; deny is then applied on top, so an event
+// type in both lists is dropped.
+func FilterMiddleware(allow, deny []string) Middleware {
+	return func(next acore.Client) acore.Client {
+		return &filterClient{
+			forwardingClient: forwardingClient{next: next},
+			allow:            allow,
+			deny:             deny,
+		}
+	}
+}
+
+type filterClient struct {
+	forwardingClient
+	allow []string
+	deny  []string
+}
+
+var _ Transport = (*filterClient)(nil)
+
+func (f *filterClient) Enqueue(m acore.APIMessage) error {
+	_, eventType, _ := probeMessage(m)
+
+	if len(f.allow) > 0 && !containsString(f.allow, eventType) {
+		return nil
+	}
+	if containsString(f.deny, eventType) {
+		return nil
+	}
+
+	return f.next.Enqueue(m)
+}
+
+func containsString(items []string, s string) bool {
+	for _, item := range items {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+package analytics
+
+import (
+	"testing"
+	"time"
+
+	"github.com/common-fate/analytics-go/acore"
+)
+
+// TestSamplingMiddlewareDeterministic confirms the same message ID always
+// samples the same way, so retries of a dropped event stay dropped instead
+// of flapping across attempts.
+func TestSamplingMiddlewareDeterministic(t *testing.T) {
+	msg := fakeMessage{MessageID: "evt_stable", Event: "test.event"}
+
+	var kept []bool
+	for i := 0; i < 5; i++ {
+		next := &fakeTransport{}
+		client := SamplingMiddleware(0.5, nil)(next)
+		if err := client.Enqueue(msg); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+		kept = append(kept, len(next.received) == 1)
+	}
+
+	for i, k := range kept {
+		if k != kept[0] {
+			t.Fatalf("sampling decision for the same message ID flapped: attempt %d = %v, attempt 0 = %v", i, k, kept[0])
+		}
+	}
+}
+
+// TestSamplingMiddlewareBounds confirms rate>=1 always keeps and rate<=0
+// always drops, regardless of the message ID's hash.
+func TestSamplingMiddlewareBounds(t *testing.T) {
+	for _, id := range []string{"a", "b", "c", "d", "e"} {
+		msg := fakeMessage{MessageID: id, Event: "test.event"}
+
+		keepAll := &fakeTransport{}
+		if err := SamplingMiddleware(1, nil)(keepAll).Enqueue(msg); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+		if len(keepAll.received) != 1 {
+			t.Fatalf("rate=1 dropped message %q", id)
+		}
+
+		dropAll := &fakeTransport{}
+		if err := SamplingMiddleware(0, nil)(dropAll).Enqueue(msg); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+		if len(dropAll.received) != 0 {
+			t.Fatalf("rate=0 kept message %q", id)
+		}
+	}
+}
+
+// TestSamplingMiddlewarePerType confirms a perType override takes
+// precedence over the default rate for matching event types.
+func TestSamplingMiddlewarePerType(t *testing.T) {
+	msg := fakeMessage{MessageID: "evt_1", Event: "important"}
+
+	next := &fakeTransport{}
+	client := SamplingMiddleware(0, map[string]float64{"important": 1})(next)
+	if err := client.Enqueue(msg); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	if len(next.received) != 1 {
+		t.Fatal("perType override did not take precedence over the default rate")
+	}
+}
+
+// TestFilterMiddlewareAllowDeny confirms allow restricts to a set of event
+// types and deny then drops from within that set.
+func TestFilterMiddlewareAllowDeny(t *testing.T) {
+	cases := []struct {
+		name  string
+		allow []string
+		deny  []string
+		event string
+		want  bool
+	}{
+		{"no lists passes everything", nil, nil, "anything", true},
+		{"allow list excludes non-members", []string{"a", "b"}, nil, "c", false},
+		{"allow list includes members", []string{"a", "b"}, nil, "a", true},
+		{"deny overrides allow", []string{"a"}, []string{"a"}, "a", false},
+		{"deny alone drops matches", nil, []string{"a"}, "a", false},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			next := &fakeTransport{}
+			client := FilterMiddleware(tc.allow, tc.deny)(next)
+			msg := fakeMessage{MessageID: "evt_1", Event: tc.event}
+			if err := client.Enqueue(msg); err != nil {
+				t.Fatalf("Enqueue: %v", err)
+			}
+			got := len(next.received) == 1
+			if got != tc.want {
+				t.Fatalf("event %q: got passed=%v, want %v", tc.event, got, tc.want)
+			}
+		})
+	}
+}
+
+// TestRateLimitMiddlewareDrop confirms events beyond burst are dropped once
+// the bucket is empty, under DropOrBlock = Drop.
+func TestRateLimitMiddlewareDrop(t *testing.T) {
+	next := &fakeTransport{}
+	client := RateLimitMiddleware(1, 1, Drop)(next)
+
+	for i := 0; i < 3; i++ {
+		if err := client.Enqueue(fakeMessage{MessageID: "evt", Event: "e"}); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+
+	if len(next.received) != 1 {
+		t.Fatalf("expected burst of 1 to admit exactly 1 event, got %d", len(next.received))
+	}
+}
+
+// TestRateLimitMiddlewareBlock confirms DropOrBlock = Block waits for a
+// token instead of dropping, so every event is eventually delivered.
+func TestRateLimitMiddlewareBlock(t *testing.T) {
+	next := &fakeTransport{}
+	client := RateLimitMiddleware(20, 1, Block)(next)
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		if err := client.Enqueue(fakeMessage{MessageID: "evt", Event: "e"}); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+	elapsed := time.Since(start)
+
+	if len(next.received) != 3 {
+		t.Fatalf("expected all 3 events to be delivered under Block, got %d", len(next.received))
+	}
+	if elapsed <= 0 {
+		t.Fatal("expected Block to take a non-zero amount of time waiting for tokens")
+	}
+}
+
+var _ acore.Client = &fakeTransport{}
@@ -0,0 +1,217 @@
+package analytics
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/common-fate/analytics-go/acore"
+	"go.uber.org/zap"
+)
+
+const spoolFileName = "cf-analytics-spool.ndjson"
+
+// SpoolTransport wraps another Transport and writes events to a
+// newline-delimited JSON file under Dir when Next is unreachable, draining
+// them back to Next on the next Enqueue or Close once it accepts events
+// again. This keeps
+// CLI users on flaky networks from silently losing events.
+type SpoolTransport struct {
+	Next Transport
+	Dir  string
+	// Logger receives an error if rewriting the spool file after a drain
+	// fails, since that's the one way this transport can still lose events
+	// despite its whole purpose being not to. Defaults to a no-op logger.
+	Logger *zap.Logger
+
+	mu   sync.Mutex
+	path string
+}
+
+var (
+	_ Transport       = (*SpoolTransport)(nil)
+	_ DeploymentAware = (*SpoolTransport)(nil)
+)
+
+// NewSpoolTransport creates a SpoolTransport that spools to dir when next is
+// unreachable.
+func NewSpoolTransport(next Transport, dir string) *SpoolTransport {
+	return &SpoolTransport{
+		Next:   next,
+		Dir:    dir,
+		Logger: zap.NewNop(),
+		path:   filepath.Join(dir, spoolFileName),
+	}
+}
+
+// logger returns s.Logger, falling back to a no-op logger so callers don't
+// need a nil check when SpoolTransport is constructed as a struct literal.
+func (s *SpoolTransport) logger() *zap.Logger {
+	if s.Logger != nil {
+		return s.Logger
+	}
+	return zap.NewNop()
+}
+
+// SetDeploymentID forwards to Next if it implements DeploymentAware.
+func (s *SpoolTransport) SetDeploymentID(id string) {
+	if da, ok := s.Next.(DeploymentAware); ok {
+		da.SetDeploymentID(id)
+	}
+}
+
+// EndpointURL implements acore.Client.
+func (s *SpoolTransport) EndpointURL() string {
+	return s.Next.EndpointURL()
+}
+
+// Close drains any spooled events before closing Next.
+func (s *SpoolTransport) Close() error {
+	s.drain()
+	return s.Next.Close()
+}
+
+// Enqueue drains any previously spooled events, then attempts to deliver m
+// via Next. If Next fails, m is appended to the spool file instead of being
+// dropped.
+func (s *SpoolTransport) Enqueue(m acore.APIMessage) error {
+	s.drain()
+
+	if err := s.Next.Enqueue(m); err != nil {
+		return s.spool(m)
+	}
+
+	return nil
+}
+
+// drain replays spooled events to Next in order, stopping at the first
+// failure and leaving the remainder on disk for the next attempt.
+//
+// It reads lines with bufio.Reader.ReadBytes rather than bufio.Scanner,
+// which caps a single token at its buffer size (~64KB by default) and
+// errors out on anything longer; that error was previously left
+// unchecked, so an oversized spooled line silently truncated the file and
+// dropped every event after it. ReadBytes has no such limit.
+func (s *SpoolTransport) drain() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	f, err := os.Open(s.path)
+	if err != nil {
+		return
+	}
+	defer f.Close()
+
+	var remaining [][]byte
+	failed := false
+	reader := bufio.NewReader(f)
+	for {
+		line, readErr := reader.ReadBytes('\n')
+		line = bytes.TrimRight(line, "\n")
+
+		if len(line) > 0 {
+			if failed {
+				remaining = append(remaining, line)
+			} else if err := s.Next.Enqueue(rawMessage(line)); err != nil {
+				failed = true
+				remaining = append(remaining, line)
+			}
+		}
+
+		if readErr != nil {
+			break
+		}
+	}
+
+	s.rewriteLocked(remaining)
+}
+
+// spoolAsync spools m after an asynchronous delivery failure reported
+// through debugCallback, since the default batched transport's Enqueue
+// returns before delivery is attempted and so never drives Enqueue's own
+// synchronous spool check. See the callback.onFailure wiring in New.
+func (s *SpoolTransport) spoolAsync(m acore.APIMessage, err error) {
+	_ = s.spool(m)
+}
+
+// spool appends m to the spool file, creating Dir if it doesn't exist yet.
+func (s *SpoolTransport) spool(m acore.APIMessage) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if err := os.MkdirAll(s.Dir, 0o700); err != nil {
+		return fmt.Errorf("creating spool dir: %w", err)
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("marshalling spooled event: %w", err)
+	}
+
+	f, err := os.OpenFile(s.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("opening spool file: %w", err)
+	}
+	defer f.Close()
+
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// rewriteLocked replaces the spool file's contents with lines, or removes it
+// entirely if lines is empty. Each entry is already a complete JSON
+// encoding of one spooled event, so it's written back verbatim. Callers
+// must hold s.mu.
+//
+// The file is already truncated by the time any write here can fail, so a
+// failure partway through would otherwise discard the remaining spooled
+// events with nothing to show for it; every failure path is logged so an
+// operator has a chance to notice.
+func (s *SpoolTransport) rewriteLocked(lines [][]byte) {
+	if len(lines) == 0 {
+		if err := os.Remove(s.path); err != nil && !os.IsNotExist(err) {
+			s.logger().Error("removing drained spool file", zap.Error(err))
+		}
+		return
+	}
+
+	f, err := os.OpenFile(s.path, os.O_TRUNC|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		s.logger().Error("rewriting spool file", zap.Int("events.lost", len(lines)), zap.Error(err))
+		return
+	}
+	defer f.Close()
+
+	w := bufio.NewWriter(f)
+	for i, line := range lines {
+		if _, err := w.Write(line); err != nil {
+			s.logger().Error("rewriting spool file", zap.Int("events.lost", len(lines)-i), zap.Error(err))
+			return
+		}
+		if err := w.WriteByte('\n'); err != nil {
+			s.logger().Error("rewriting spool file", zap.Int("events.lost", len(lines)-i), zap.Error(err))
+			return
+		}
+	}
+	if err := w.Flush(); err != nil {
+		s.logger().Error("rewriting spool file", zap.Error(err))
+	}
+}
+
+// rawMessage replays a spooled event's pre-encoded JSON bytes as an
+// acore.APIMessage. acore.APIMessage is an interface, and json.Unmarshal
+// has no concrete type to decode a spooled line into; rawMessage sidesteps
+// that by carrying the exact bytes SpoolTransport originally wrote and
+// re-emitting them unchanged from MarshalJSON.
+type rawMessage []byte
+
+// MarshalJSON implements json.Marshaler.
+func (m rawMessage) MarshalJSON() ([]byte, error) {
+	return m, nil
+}
+
+var _ acore.APIMessage = rawMessage(nil)
@@ -0,0 +1,167 @@
+package analytics
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/common-fate/analytics-go/acore"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+)
+
+// fakeTransport records every message Enqueue receives as its encoded JSON,
+// and fails every Enqueue while fail is true. It exists to drive
+// SpoolTransport without depending on acore's real HTTP transport.
+type fakeTransport struct {
+	received []json.RawMessage
+	fail     bool
+}
+
+func (f *fakeTransport) Enqueue(m acore.APIMessage) error {
+	if f.fail {
+		return fmt.Errorf("endpoint unreachable")
+	}
+
+	data, err := json.Marshal(m)
+	if err != nil {
+		return err
+	}
+	f.received = append(f.received, append(json.RawMessage(nil), data...))
+	return nil
+}
+
+func (f *fakeTransport) Close() error        { return nil }
+func (f *fakeTransport) EndpointURL() string { return "fake://endpoint" }
+
+type fakeMessage struct {
+	MessageID string `json:"messageId"`
+	Event     string `json:"event"`
+}
+
+// TestSpoolTransportDrainRoundTrip spools a message while Next is down, then
+// confirms that once Next recovers, drain redelivers the exact same message
+// instead of silently discarding it (each spooled line is redelivered
+// verbatim as a rawMessage, not round-tripped through acore.APIMessage,
+// which is an interface json.Unmarshal can't populate).
+func TestSpoolTransportDrainRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	next := &fakeTransport{fail: true}
+	spool := NewSpoolTransport(next, dir)
+
+	msg := fakeMessage{MessageID: "evt_1", Event: "test.event"}
+	if err := spool.Enqueue(msg); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, spoolFileName)); err != nil {
+		t.Fatalf("expected spool file to exist: %v", err)
+	}
+
+	next.fail = false
+	spool.drain()
+
+	if len(next.received) != 1 {
+		t.Fatalf("expected 1 redelivered message, got %d", len(next.received))
+	}
+
+	var got fakeMessage
+	if err := json.Unmarshal(next.received[0], &got); err != nil {
+		t.Fatalf("unmarshalling redelivered message: %v", err)
+	}
+	if got != msg {
+		t.Fatalf("redelivered message = %+v, want %+v", got, msg)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, spoolFileName)); !os.IsNotExist(err) {
+		t.Fatalf("expected spool file to be removed after a successful drain, got err=%v", err)
+	}
+}
+
+// TestSpoolTransportDrainOversizedLine spools an event with a payload well
+// past bufio.Scanner's default ~64KB token limit, sandwiched between two
+// small ones, and confirms drain redelivers all three instead of losing the
+// oversized line and everything after it.
+func TestSpoolTransportDrainOversizedLine(t *testing.T) {
+	dir := t.TempDir()
+	next := &fakeTransport{}
+	spool := NewSpoolTransport(next, dir)
+
+	large := fakeMessage{MessageID: "evt_2", Event: strings.Repeat("x", 70*1024)}
+	msgs := []fakeMessage{
+		{MessageID: "evt_1", Event: "small.one"},
+		large,
+		{MessageID: "evt_3", Event: "small.two"},
+	}
+
+	var content []byte
+	for _, m := range msgs {
+		data, err := json.Marshal(m)
+		if err != nil {
+			t.Fatalf("marshalling message: %v", err)
+		}
+		content = append(content, data...)
+		content = append(content, '\n')
+	}
+	if err := os.WriteFile(filepath.Join(dir, spoolFileName), content, 0o600); err != nil {
+		t.Fatalf("writing spool file: %v", err)
+	}
+
+	spool.drain()
+
+	if len(next.received) != len(msgs) {
+		t.Fatalf("expected %d redelivered messages, got %d", len(msgs), len(next.received))
+	}
+
+	for i, raw := range next.received {
+		var got fakeMessage
+		if err := json.Unmarshal(raw, &got); err != nil {
+			t.Fatalf("unmarshalling redelivered message %d: %v", i, err)
+		}
+		if got != msgs[i] {
+			t.Fatalf("redelivered message %d = %+v, want %+v", i, got, msgs[i])
+		}
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, spoolFileName)); !os.IsNotExist(err) {
+		t.Fatalf("expected spool file to be removed after a successful drain, got err=%v", err)
+	}
+}
+
+// TestSpoolTransportRewriteLockedLogsFailure confirms that when rewriting
+// the spool file after a partial drain fails, the loss is logged rather
+// than silently swallowed - the file is already truncated by that point, so
+// logging is the only way an operator can notice lost events.
+func TestSpoolTransportRewriteLockedLogsFailure(t *testing.T) {
+	dir := t.TempDir()
+
+	// A directory in place of the spool file: opening it for writing always
+	// fails, regardless of permissions, simulating a rewrite failure.
+	blocked := filepath.Join(dir, "blocked")
+	if err := os.Mkdir(blocked, 0o700); err != nil {
+		t.Fatalf("creating blocked path: %v", err)
+	}
+
+	core, logs := observer.New(zapcore.ErrorLevel)
+	spool := NewSpoolTransport(&fakeTransport{}, dir)
+	spool.path = blocked
+	spool.Logger = zap.New(core)
+
+	spool.rewriteLocked([][]byte{[]byte(`{"messageId":"evt_1"}`)})
+
+	entries := logs.All()
+	if len(entries) != 1 {
+		t.Fatalf("expected rewriteLocked to log exactly 1 error, got %d", len(entries))
+	}
+	lost, ok := entries[0].ContextMap()["events.lost"]
+	if !ok {
+		t.Fatal("expected an events.lost field on the logged error")
+	}
+	if n, ok := lost.(int64); !ok || n != 1 {
+		t.Fatalf("events.lost = %v, want 1", lost)
+	}
+}
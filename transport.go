@@ -0,0 +1,80 @@
+package analytics
+
+import (
+	"github.com/common-fate/analytics-go/acore"
+)
+
+// Transport is implemented by anything capable of delivering analytics
+// events on behalf of a Client. It is the same interface acore uses for its
+// own HTTP client, which keeps the default path (batched JSON posted to
+// Config.Endpoint) and the alternatives below interchangeable.
+type Transport = acore.Client
+
+// DeploymentAware is implemented by transports that want to know the
+// deployment ID as soon as it's set, e.g. to stamp it into outgoing events.
+// Client.SetDeploymentID and Client.SetDeployment notify the configured
+// Transport if it implements this interface.
+type DeploymentAware interface {
+	SetDeploymentID(id string)
+}
+
+// Format selects the wire format used when Config.Transport is not set
+// explicitly. It has no effect if Config.Transport is provided.
+type Format string
+
+const (
+	// FormatJSON posts batched events as JSON to Config.Endpoint. This is
+	// the default and is understood by t.commonfate.io.
+	FormatJSON Format = "json"
+	// FormatCloudEventsBinary sends each event as a CloudEvents v1.0
+	// message using the binary content mode (attributes as HTTP headers).
+	FormatCloudEventsBinary Format = "cloudevents-binary"
+	// FormatCloudEventsStructured sends each event as a CloudEvents v1.0
+	// message using the structured content mode (attributes and data
+	// combined into a single JSON body).
+	FormatCloudEventsStructured Format = "cloudevents-structured"
+)
+
+// Option customises a Config before it is used to build a Client.
+type Option func(*Config)
+
+// WithTransport overrides the transport used to deliver events. It takes
+// precedence over Config.Transport and Config.Format if either is also set.
+func WithTransport(t Transport) Option {
+	return func(c *Config) {
+		c.Transport = t
+	}
+}
+
+// buildTransport returns the acore.Client to use for c, honouring
+// Config.Transport and Config.Format, with every path logging through
+// callback so Config.Logger sees structured success/failure fields
+// regardless of which transport is in use. It does not apply SpoolDir;
+// callers wrap the result in a SpoolTransport themselves if that's
+// configured.
+func buildTransport(c Config, callback *debugCallback) (Transport, error) {
+	if c.Transport != nil {
+		return loggingClient{forwardingClient: forwardingClient{next: c.Transport}, callback: callback}, nil
+	}
+
+	switch c.Format {
+	case FormatCloudEventsBinary, FormatCloudEventsStructured:
+		ce := NewCloudEventsTransport(CloudEventsConfig{
+			Endpoint: endpointOrDefault(c.Endpoint),
+			Format:   c.Format,
+		})
+		return loggingClient{forwardingClient: forwardingClient{next: ce}, callback: callback}, nil
+	default:
+		client, err := acore.NewWithConfig(acore.Config{
+			Endpoint:  c.Endpoint,
+			Callback:  callback,
+			Verbose:   c.Verbose,
+			Interval:  defaultInterval,
+			BatchSize: defaultBatchSize,
+		})
+		if err != nil {
+			return nil, err
+		}
+		return callbackInstrumentedClient{Client: client, callback: callback}, nil
+	}
+}
@@ -0,0 +1,77 @@
+package analytics
+
+import (
+	"testing"
+
+	"go.uber.org/zap"
+)
+
+// TestBuildTransportUsesConfigTransport confirms a caller-supplied
+// Config.Transport is wrapped in loggingClient and used as-is, rather than
+// being routed through Config.Format.
+func TestBuildTransportUsesConfigTransport(t *testing.T) {
+	custom := &fakeTransport{}
+	callback := &debugCallback{logger: zap.NewNop(), endpoint: "fake://endpoint"}
+
+	client, err := buildTransport(Config{
+		Transport: custom,
+		Format:    FormatCloudEventsBinary, // should be ignored: Transport wins
+	}, callback)
+	if err != nil {
+		t.Fatalf("buildTransport: %v", err)
+	}
+
+	lc, ok := client.(loggingClient)
+	if !ok {
+		t.Fatalf("expected a loggingClient, got %T", client)
+	}
+	if lc.next != custom {
+		t.Fatalf("expected the loggingClient to wrap the supplied Config.Transport, wrapped %T instead", lc.next)
+	}
+}
+
+// TestBuildTransportCloudEventsFormat confirms Config.Format routes to a
+// CloudEventsTransport, in both content modes, when Config.Transport is
+// unset.
+func TestBuildTransportCloudEventsFormat(t *testing.T) {
+	for _, format := range []Format{FormatCloudEventsBinary, FormatCloudEventsStructured} {
+		t.Run(string(format), func(t *testing.T) {
+			callback := &debugCallback{logger: zap.NewNop(), endpoint: "fake://endpoint"}
+
+			client, err := buildTransport(Config{
+				Endpoint: "https://example.invalid",
+				Format:   format,
+			}, callback)
+			if err != nil {
+				t.Fatalf("buildTransport: %v", err)
+			}
+
+			lc, ok := client.(loggingClient)
+			if !ok {
+				t.Fatalf("expected a loggingClient, got %T", client)
+			}
+			ce, ok := lc.next.(*CloudEventsTransport)
+			if !ok {
+				t.Fatalf("expected the loggingClient to wrap a *CloudEventsTransport, wrapped %T instead", lc.next)
+			}
+			if ce.format != format {
+				t.Fatalf("CloudEventsTransport.format = %q, want %q", ce.format, format)
+			}
+		})
+	}
+}
+
+// TestWithTransportPrecedence confirms WithTransport overrides whatever
+// Config.Transport was already set, matching its doc comment, since New
+// applies Options after the caller's Config is in hand.
+func TestWithTransportPrecedence(t *testing.T) {
+	original := &fakeTransport{}
+	preferred := &fakeTransport{}
+
+	cfg := Config{Transport: original}
+	WithTransport(preferred)(&cfg)
+
+	if cfg.Transport != preferred {
+		t.Fatalf("expected WithTransport to override Config.Transport, got %T", cfg.Transport)
+	}
+}